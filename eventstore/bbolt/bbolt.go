@@ -3,17 +3,31 @@ package bbolt
 import (
 	"context"
 	"encoding/binary"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"sync"
 	"time"
 
+	"github.com/hallgren/eventsourcing/base"
 	"github.com/hallgren/eventsourcing/core"
 	"go.etcd.io/bbolt"
 )
 
 const (
 	globalEventOrderBucketName = "global_event_order"
+
+	// recordMagic identifies a record written by this package so that foreign or
+	// corrupt data can be told apart from a real event record.
+	recordMagic = "ESB1"
+
+	// currentSchemaVersion is bumped whenever the shape of boltEvent changes in a
+	// way that a reader needs to know about.
+	currentSchemaVersion = uint32(1)
+
+	// recordHeaderLen is the magic + format id + schema version prefix that is
+	// written before every serialized boltEvent.
+	recordHeaderLen = len(recordMagic) + 2 + 4
 )
 
 // itob returns an 8-byte big endian representation of v.
@@ -25,7 +39,12 @@ func itob(v uint64) []byte {
 
 // BBolt is the eventstore handler
 type BBolt struct {
-	db *bbolt.DB // The bbolt db where we store everything
+	db         *bbolt.DB // The bbolt db where we store everything
+	serializer base.Serializer
+	formatID   uint16 // identifies records written with serializer, see formatID()
+
+	mu     sync.Mutex
+	notify chan struct{} // closed and replaced every time Save or Tx.Commit commits, see wait/broadcast
 }
 
 type boltEvent struct {
@@ -39,14 +58,25 @@ type boltEvent struct {
 	Metadata      []byte // map[string]interface{}
 }
 
-// MustOpenBBolt opens the event stream found in the given file. If the file is not found it will be created and
-// initialized. Will panic if it has problems persisting the changes to the filesystem.
-func MustOpenBBolt(dbFile string) *BBolt {
+// MustOpenBBolt opens the event stream found in the given file using ser to encode and decode the
+// stored events. If the file is not found it will be created and initialized. Will panic if it has
+// problems persisting the changes to the filesystem.
+func MustOpenBBolt(dbFile string, ser base.Serializer) *BBolt {
+	e, err := OpenBBolt(dbFile, ser)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// OpenBBolt opens the event stream found in the given file using ser to encode and decode the
+// stored events. If the file is not found it will be created and initialized.
+func OpenBBolt(dbFile string, ser base.Serializer) (*BBolt, error) {
 	db, err := bbolt.Open(dbFile, 0600, &bbolt.Options{
 		Timeout: 1 * time.Second,
 	})
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	// Ensure that we have a bucket to store the global event ordering
@@ -57,11 +87,108 @@ func MustOpenBBolt(dbFile string) *BBolt {
 		return nil
 	})
 	if err != nil {
-		panic(err)
+		db.Close()
+		return nil, err
 	}
 	return &BBolt{
-		db: db,
+		db:         db,
+		serializer: ser,
+		formatID:   formatID(ser),
+		notify:     make(chan struct{}),
+	}, nil
+}
+
+// wait returns the channel that will be closed the next time Save or Tx.Commit commits, so that a
+// caller can block until new events are available instead of polling.
+func (e *BBolt) wait() <-chan struct{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.notify
+}
+
+// broadcast wakes up everyone currently blocked in wait.
+func (e *BBolt) broadcast() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	close(e.notify)
+	e.notify = make(chan struct{})
+}
+
+// formatID derives a stable identifier for a serializer from its concrete type so that records
+// written with different serializers can be told apart once stored side by side in the same
+// database.
+func formatID(ser base.Serializer) uint16 {
+	h := fnv.New32a()
+	h.Write([]byte(fmt.Sprintf("%T", ser)))
+	return uint16(h.Sum32())
+}
+
+// encodeRecord serializes ev with the store's serializer and prefixes the result with a small
+// header (magic bytes, format id and schema version) so that the record can be recognised again
+// even after the store has been reopened with a different serializer.
+func (e *BBolt) encodeRecord(ev boltEvent) ([]byte, error) {
+	body, err := e.serializer.Marshal(ev)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("could not serialize event, %v", err))
+	}
+	record := make([]byte, 0, recordHeaderLen+len(body))
+	record = append(record, recordMagic...)
+	record = binary.BigEndian.AppendUint16(record, e.formatID)
+	record = binary.BigEndian.AppendUint32(record, currentSchemaVersion)
+	record = append(record, body...)
+	return record, nil
+}
+
+// decodeRecord reads the header off a record and unmarshals the remaining body with ser. It
+// returns the format id the record was written with so that callers can tell apart records from a
+// serializer other than the one passed in.
+func decodeRecord(ser base.Serializer, data []byte) (boltEvent, uint16, error) {
+	ev := boltEvent{}
+	if len(data) < recordHeaderLen || string(data[:len(recordMagic)]) != recordMagic {
+		return ev, 0, errors.New("not a valid event record")
+	}
+	fID := binary.BigEndian.Uint16(data[len(recordMagic) : len(recordMagic)+2])
+	err := ser.Unmarshal(data[recordHeaderLen:], &ev)
+	return ev, fID, err
+}
+
+// Migrate rewrites every event currently encoded with oldSerializer to the store's active
+// serializer, in a single bolt transaction. Run it once after swapping to a new serializer so that
+// records written before the swap stay readable.
+func (e *BBolt) Migrate(oldSerializer base.Serializer) error {
+	oldFormatID := formatID(oldSerializer)
+	if oldFormatID == e.formatID {
+		return nil
+	}
+
+	tx, err := e.db.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.ForEach(func(name []byte, bucket *bbolt.Bucket) error {
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			ev, fID, err := decodeRecord(oldSerializer, v)
+			if err != nil || fID != oldFormatID {
+				// already migrated, or written with yet another serializer
+				continue
+			}
+			record, err := e.encodeRecord(ev)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(k, record); err != nil {
+				return errors.New(fmt.Sprintf("could not migrate event %#v in bucket %s", ev, name))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	return tx.Commit()
 }
 
 // Save an aggregate (its events)
@@ -71,21 +198,55 @@ func (e *BBolt) Save(events []core.Event) error {
 		return nil
 	}
 
-	// get bucket name from first event
-	aggregateType := events[0].AggregateType
-	aggregateID := events[0].AggregateID
-	bucketName := aggregateKey(aggregateType, aggregateID)
-
 	tx, err := e.db.Begin(true)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if err := e.saveGroup(tx, events); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	e.broadcast()
+	return nil
+}
+
+// SaveTx groups events by aggregate (AggregateType, AggregateID) and saves every group within a
+// single bolt transaction: either all of them commit, or, if any group fails its
+// optimistic-concurrency check, none do and core.ErrConcurrency is returned naming the offending
+// aggregate.
+func (e *BBolt) SaveTx(events []core.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := e.BeginTx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.Save(events); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// saveGroup persists a single aggregate's events (all sharing the same AggregateType and
+// AggregateID) within tx, verifying the expected version against what is already stored.
+func (e *BBolt) saveGroup(tx *bbolt.Tx, events []core.Event) error {
+	// get bucket name from first event
+	aggregateType := events[0].AggregateType
+	aggregateID := events[0].AggregateID
+	bucketName := aggregateKey(aggregateType, aggregateID)
+
 	evBucket := tx.Bucket([]byte(bucketName))
 	if evBucket == nil {
 		// Ensure that we have a bucket named events_aggregateType_aggregateID for the given aggregate
-		err = e.createBucket([]byte(bucketName), tx)
+		err := e.createBucket([]byte(bucketName), tx)
 		if err != nil {
 			return errors.New("could not create aggregate events bucket")
 		}
@@ -96,17 +257,21 @@ func (e *BBolt) Save(events []core.Event) error {
 	cursor := evBucket.Cursor()
 	k, obj := cursor.Last()
 	if k != nil {
-		lastEvent := boltEvent{}
-		err := json.Unmarshal(obj, &lastEvent)
+		lastEvent, _, err := decodeRecord(e.serializer, obj)
 		if err != nil {
-			return errors.New(fmt.Sprintf("could not serialize event, %v", err))
+			return errors.New(fmt.Sprintf("could not deserialize event, %v", err))
 		}
 		currentVersion = lastEvent.Version
+	} else if version, ok := snapshotVersion(tx, bucketName); ok {
+		// the bucket has no events of its own, which is also what a brand new aggregate looks
+		// like - but if Compact already dropped this aggregate's events because a snapshot
+		// covers it, the recorded snapshot version is the real current version, not zero.
+		currentVersion = uint64(version)
 	}
 
 	// Make sure no other has saved event to the same aggregate concurrently
 	if core.Version(currentVersion)+1 != events[0].Version {
-		return core.ErrConcurrency
+		return fmt.Errorf("%w: %s", core.ErrConcurrency, bucketName)
 	}
 
 	globalBucket := tx.Bucket([]byte(globalEventOrderBucketName))
@@ -141,9 +306,9 @@ func (e *BBolt) Save(events []core.Event) error {
 			Data:          event.Data,
 		}
 
-		value, err := json.Marshal(bEvent)
+		value, err := e.encodeRecord(bEvent)
 		if err != nil {
-			return errors.New(fmt.Sprintf("could not serialize event, %v", err))
+			return err
 		}
 
 		err = evBucket.Put(itob(sequence), value)
@@ -158,7 +323,77 @@ func (e *BBolt) Save(events []core.Event) error {
 		// override the event in the slice exposing the GlobalVersion to the caller
 		events[i].GlobalVersion = core.Version(globalSequence)
 	}
-	return tx.Commit()
+	return nil
+}
+
+// aggregateKeyPair identifies the bucket a group of events belongs to.
+type aggregateKeyPair struct {
+	aggregateType string
+	aggregateID   string
+}
+
+// groupByAggregate groups events by (AggregateType, AggregateID), preserving the order in which
+// each aggregate is first seen.
+func groupByAggregate(events []core.Event) [][]core.Event {
+	order := []aggregateKeyPair{}
+	groups := map[aggregateKeyPair][]core.Event{}
+	for _, ev := range events {
+		k := aggregateKeyPair{ev.AggregateType, ev.AggregateID}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], ev)
+	}
+
+	result := make([][]core.Event, len(order))
+	for i, k := range order {
+		result[i] = groups[k]
+	}
+	return result
+}
+
+// Tx is a bolt write transaction opened via BBolt.BeginTx. It lets higher layers, such as
+// EventRepository or SnapshotRepository, enlist several Save calls - or a snapshot write sharing
+// the same underlying bolt.DB - in one atomic commit.
+type Tx struct {
+	e  *BBolt
+	tx *bbolt.Tx
+}
+
+// BeginTx starts a new write transaction. Events saved with Tx.Save take part in it until Commit
+// or Rollback is called.
+func (e *BBolt) BeginTx() (*Tx, error) {
+	tx, err := e.db.Begin(true)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{e: e, tx: tx}, nil
+}
+
+// Save persists events as part of this transaction, following the same grouping and
+// optimistic-concurrency rules as BBolt.SaveTx.
+func (t *Tx) Save(events []core.Event) error {
+	for _, group := range groupByAggregate(events) {
+		if err := t.e.saveGroup(t.tx, group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Commit commits every write made through this transaction.
+func (t *Tx) Commit() error {
+	if err := t.tx.Commit(); err != nil {
+		return err
+	}
+	t.e.broadcast()
+	return nil
+}
+
+// Rollback discards every write made through this transaction. Calling it after a successful
+// Commit, or more than once, changes nothing and returns bbolt.ErrTxClosed, matching bolt.Tx.
+func (t *Tx) Rollback() error {
+	return t.tx.Rollback()
 }
 
 // Get aggregate events
@@ -172,10 +407,10 @@ func (e *BBolt) Get(ctx context.Context, id string, aggregateType string, afterV
 	if bucket == nil {
 		tx.Rollback()
 		// no aggregate event stream
-		return core.ZeroIterator{}, nil
+		return zeroIterator{}, nil
 	}
 	cursor := bucket.Cursor()
-	return &iterator{tx: tx, cursor: cursor, startPosition: position(afterVersion)}, nil
+	return &iterator{tx: tx, cursor: cursor, serializer: e.serializer, startPosition: position(afterVersion)}, nil
 
 }
 
@@ -189,7 +424,7 @@ func (e *BBolt) GlobalEvents(start uint64) (core.Iterator, error) {
 	globalBucket := tx.Bucket([]byte(globalEventOrderBucketName))
 	cursor := globalBucket.Cursor()
 
-	return &iterator{tx: tx, cursor: cursor, startPosition: position(core.Version(start))}, nil
+	return &iterator{tx: tx, cursor: cursor, serializer: e.serializer, startPosition: position(core.Version(start))}, nil
 }
 
 // Close closes the event stream and the underlying database
@@ -0,0 +1,32 @@
+package bbolt_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/hallgren/eventsourcing/eventstore/bbolt"
+)
+
+// jsonSerializer is a minimal base.Serializer used by the feature tests in this package. The
+// conformance test in TestSuite exercises the suite's own serializers; these tests only need one
+// concrete implementation to drive BBolt directly.
+type jsonSerializer struct{}
+
+func (jsonSerializer) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonSerializer) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// openTestBBolt opens a BBolt store backed by a fresh file in t.TempDir(), closed automatically
+// when the test ends.
+func openTestBBolt(t *testing.T) *bbolt.BBolt {
+	t.Helper()
+	dbFile := filepath.Join(t.TempDir(), "bolt.db")
+	es := bbolt.MustOpenBBolt(dbFile, jsonSerializer{})
+	t.Cleanup(func() { es.Close() })
+	return es
+}
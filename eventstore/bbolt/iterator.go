@@ -1,18 +1,31 @@
 package bbolt
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 
+	"github.com/hallgren/eventsourcing/base"
 	"github.com/hallgren/eventsourcing/core"
 	"go.etcd.io/bbolt"
 )
 
+// zeroIterator is an empty core.Iterator, returned when there is nothing to iterate over, e.g.
+// because the requested aggregate has no event stream yet.
+type zeroIterator struct{}
+
+func (zeroIterator) Next() bool { return false }
+func (zeroIterator) Value() (core.Event, error) {
+	return core.Event{}, errors.New("no value, Next returned false")
+}
+func (zeroIterator) Close() {}
+
 type iterator struct {
-	tx     *bbolt.Tx
-	cursor *bbolt.Cursor
-	value  []byte
+	tx            *bbolt.Tx
+	cursor        *bbolt.Cursor
+	serializer    base.Serializer
+	startPosition []byte
+	started       bool
+	value         []byte
 }
 
 // Close closes the iterator
@@ -22,7 +35,13 @@ func (i *iterator) Close() {
 
 func (i *iterator) Next() bool {
 	var value []byte
-	_, value = i.cursor.Next()
+	if !i.started {
+		// the cursor has not been positioned yet, seek to where this iterator should start
+		i.started = true
+		_, value = i.cursor.Seek(i.startPosition)
+	} else {
+		_, value = i.cursor.Next()
+	}
 
 	if value == nil {
 		return false
@@ -33,8 +52,7 @@ func (i *iterator) Next() bool {
 
 // Next return the next event
 func (i *iterator) Value() (core.Event, error) {
-	bEvent := boltEvent{}
-	err := json.Unmarshal(i.value, &bEvent)
+	bEvent, _, err := decodeRecord(i.serializer, i.value)
 	if err != nil {
 		return core.Event{}, errors.New(fmt.Sprintf("could not deserialize event, %v", err))
 	}
@@ -0,0 +1,259 @@
+package bbolt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/hallgren/eventsourcing/core"
+	"go.etcd.io/bbolt"
+)
+
+// snapshotVersionBucketName stores, per aggregate bucket name, the version up to which a snapshot
+// is known to exist. Compact uses it to decide whether an aggregate's old events are safe to drop.
+const snapshotVersionBucketName = "snapshot_versions"
+
+// archiveHeaderLen is the 8-byte global sequence plus the 4-byte record length written before
+// every record in an Archive file.
+const archiveHeaderLen = 8 + 4
+
+// RecordSnapshotVersion notes that a snapshot for aggregateType/aggregateID now covers up to
+// version, so that Compact knows it is safe to drop older events for that aggregate once they
+// predate the retention cutoff.
+func (e *BBolt) RecordSnapshotVersion(aggregateType, aggregateID string, version core.Version) error {
+	return e.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(snapshotVersionBucketName))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(aggregateKey(aggregateType, aggregateID)), itob(uint64(version)))
+	})
+}
+
+// snapshotVersion returns the version recorded by RecordSnapshotVersion for bucketName, if any.
+func snapshotVersion(tx *bbolt.Tx, bucketName string) (core.Version, bool) {
+	bucket := tx.Bucket([]byte(snapshotVersionBucketName))
+	if bucket == nil {
+		return 0, false
+	}
+	v := bucket.Get([]byte(bucketName))
+	if v == nil {
+		return 0, false
+	}
+	return core.Version(binary.BigEndian.Uint64(v)), true
+}
+
+// compactTarget is an aggregate bucket Compact has decided to drop, along with the global
+// sequence of every event it holds so the matching entries in global_event_order can be dropped
+// too.
+type compactTarget struct {
+	name           []byte
+	globalVersions []uint64
+}
+
+// Compact drops the bucket of every aggregate whose latest event predates before, provided a
+// snapshot at or beyond that event's version has been recorded for it via RecordSnapshotVersion,
+// and removes that aggregate's events from global_event_order as well, so that GlobalEvents,
+// Subscribe and the TCP stream stop replaying them too. This leaves a gap in the global sequence
+// where the compacted events used to be; readers that tolerate missing keys (as every core.Iterator
+// in this package does) are unaffected. Aggregates without a recorded snapshot are left untouched,
+// however old their events are, since compacting them would make them unreadable. A later Save for
+// a compacted aggregate still works: saveGroup falls back to the recorded snapshot version when an
+// aggregate's bucket is empty, instead of treating it as a brand new aggregate at version 0.
+func (e *BBolt) Compact(before time.Time) error {
+	tx, err := e.db.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	globalBucket := tx.Bucket([]byte(globalEventOrderBucketName))
+	if globalBucket == nil {
+		return errors.New("global bucket not found")
+	}
+
+	// Collect the buckets to drop first: mutating a bucket while tx.ForEach is iterating it is not
+	// safe in bbolt.
+	toDelete := []compactTarget{}
+	err = tx.ForEach(func(name []byte, bucket *bbolt.Bucket) error {
+		bucketName := string(name)
+		if bucketName == globalEventOrderBucketName || bucketName == snapshotVersionBucketName || bucketName == snapshotBucketName {
+			return nil
+		}
+
+		cursor := bucket.Cursor()
+		_, obj := cursor.Last()
+		if obj == nil {
+			return nil
+		}
+		lastEvent, _, err := decodeRecord(e.serializer, obj)
+		if err != nil {
+			return err
+		}
+		if !lastEvent.Timestamp.Before(before) {
+			return nil
+		}
+
+		version, ok := snapshotVersion(tx, bucketName)
+		if !ok || version < core.Version(lastEvent.Version) {
+			return nil
+		}
+
+		globalVersions := []uint64{}
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			ev, _, err := decodeRecord(e.serializer, v)
+			if err != nil {
+				return err
+			}
+			globalVersions = append(globalVersions, ev.GlobalVersion)
+		}
+
+		toDelete = append(toDelete, compactTarget{name: append([]byte(nil), name...), globalVersions: globalVersions})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, target := range toDelete {
+		for _, globalVersion := range target.globalVersions {
+			if err := globalBucket.Delete(itob(globalVersion)); err != nil {
+				return errors.New(fmt.Sprintf("could not delete global event %d: %v", globalVersion, err))
+			}
+		}
+		if err := tx.DeleteBucket(target.name); err != nil {
+			return errors.New(fmt.Sprintf("could not delete bucket %s: %v", target.name, err))
+		}
+	}
+	return tx.Commit()
+}
+
+// Archive streams every event in the global order bucket whose global sequence is within [from,
+// to] to w, as a portable file of length-prefixed records, for cold storage. Restore replays a
+// file written by Archive back into a store.
+func (e *BBolt) Archive(w io.Writer, from, to uint64) error {
+	tx, err := e.db.Begin(false)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	globalBucket := tx.Bucket([]byte(globalEventOrderBucketName))
+	if globalBucket == nil {
+		return errors.New("global bucket not found")
+	}
+
+	header := make([]byte, archiveHeaderLen)
+	cursor := globalBucket.Cursor()
+	for k, v := cursor.Seek(itob(from)); k != nil && binary.BigEndian.Uint64(k) <= to; k, v = cursor.Next() {
+		copy(header[:8], k)
+		binary.BigEndian.PutUint32(header[8:], uint32(len(v)))
+		if _, err := w.Write(header); err != nil {
+			return err
+		}
+		if _, err := w.Write(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore replays a file written by Archive: every record is inserted into both the global order
+// bucket, at its original global sequence, and its aggregate's bucket, at its original version, so
+// the effect is the same as if the events had never been archived. It also resyncs each touched
+// bucket's sequence counter to the highest restored key, so that the next Save/SaveTx on a
+// restored aggregate continues from there instead of minting keys that collide with - and
+// silently overwrite - the just-restored events.
+func (e *BBolt) Restore(r io.Reader) error {
+	tx, err := e.db.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	globalBucket, err := tx.CreateBucketIfNotExists([]byte(globalEventOrderBucketName))
+	if err != nil {
+		return err
+	}
+
+	evBuckets := map[string]*bbolt.Bucket{}
+	maxVersion := map[string]uint64{}
+	var maxGlobalVersion uint64
+
+	header := make([]byte, archiveHeaderLen)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		key := append([]byte(nil), header[:8]...)
+		body := make([]byte, binary.BigEndian.Uint32(header[8:]))
+		if _, err := io.ReadFull(r, body); err != nil {
+			return err
+		}
+
+		if err := globalBucket.Put(key, body); err != nil {
+			return err
+		}
+		if globalVersion := binary.BigEndian.Uint64(key); globalVersion > maxGlobalVersion {
+			maxGlobalVersion = globalVersion
+		}
+
+		bEvent, _, err := decodeRecord(e.serializer, body)
+		if err != nil {
+			return err
+		}
+
+		bucketName := aggregateKey(bEvent.AggregateType, bEvent.AggregateID)
+		evBucket, ok := evBuckets[bucketName]
+		if !ok {
+			evBucket, err = tx.CreateBucketIfNotExists([]byte(bucketName))
+			if err != nil {
+				return err
+			}
+			evBuckets[bucketName] = evBucket
+		}
+		if err := evBucket.Put(itob(bEvent.Version), body); err != nil {
+			return err
+		}
+		if bEvent.Version > maxVersion[bucketName] {
+			maxVersion[bucketName] = bEvent.Version
+		}
+	}
+
+	if maxGlobalVersion > globalBucket.Sequence() {
+		if err := globalBucket.SetSequence(maxGlobalVersion); err != nil {
+			return err
+		}
+	}
+	for bucketName, version := range maxVersion {
+		evBucket := evBuckets[bucketName]
+		if version > evBucket.Sequence() {
+			if err := evBucket.SetSequence(version); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Vacuum copies the live database to dest via bolt.Tx.WriteTo, producing a compacted copy free of
+// the free-list fragmentation that accumulates from normal writes and deletes.
+func (e *BBolt) Vacuum(dest string) error {
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return e.db.View(func(tx *bbolt.Tx) error {
+		_, err := tx.WriteTo(f)
+		return err
+	})
+}
@@ -0,0 +1,159 @@
+package bbolt_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing/core"
+	"github.com/hallgren/eventsourcing/eventstore/bbolt"
+)
+
+func TestCompactDropsAggregateAndMatchingGlobalEvents(t *testing.T) {
+	es := openTestBBolt(t)
+
+	old := newEvent("a", "1", 1)
+	old.Timestamp = time.Now().Add(-48 * time.Hour)
+	if err := es.Save([]core.Event{old}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := es.RecordSnapshotVersion("a", "1", old.Version); err != nil {
+		t.Fatalf("RecordSnapshotVersion() error = %v", err)
+	}
+
+	recent := newEvent("b", "1", 1)
+	if err := es.Save([]core.Event{recent}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if err := es.Compact(time.Now().Add(-24 * time.Hour)); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+
+	it, err := es.GlobalEvents(0)
+	if err != nil {
+		t.Fatalf("GlobalEvents() error = %v", err)
+	}
+	defer it.Close()
+
+	var remaining []core.Event
+	for it.Next() {
+		ev, err := it.Value()
+		if err != nil {
+			t.Fatalf("Value() error = %v", err)
+		}
+		remaining = append(remaining, ev)
+	}
+	if len(remaining) != 1 || remaining[0].AggregateID != "1" || remaining[0].AggregateType != "b" {
+		t.Fatalf("got %+v, want only the recent 'b' event left in global order", remaining)
+	}
+}
+
+func TestCompactThenSaveContinuesTheAggregatesVersion(t *testing.T) {
+	es := openTestBBolt(t)
+
+	old := newEvent("a", "1", 1)
+	old.Timestamp = time.Now().Add(-48 * time.Hour)
+	if err := es.Save([]core.Event{old}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := es.RecordSnapshotVersion("a", "1", old.Version); err != nil {
+		t.Fatalf("RecordSnapshotVersion() error = %v", err)
+	}
+	if err := es.Compact(time.Now().Add(-24 * time.Hour)); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+
+	// the aggregate's bucket is gone, but a snapshot covers version 1, so a save continuing from
+	// version 2 must succeed instead of looking like a fresh aggregate or a concurrency conflict.
+	if err := es.Save([]core.Event{newEvent("a", "1", 2)}); err != nil {
+		t.Fatalf("Save() after Compact error = %v, want it to continue from the recorded snapshot version", err)
+	}
+}
+
+func TestArchiveRestoreRoundTripAndResumeSave(t *testing.T) {
+	src := openTestBBolt(t)
+
+	events := []core.Event{newEvent("a", "1", 1), newEvent("a", "1", 2)}
+	if err := src.Save(events); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Archive(&buf, 0, ^uint64(0)); err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+
+	dstFile := filepath.Join(t.TempDir(), "restored.db")
+	dst := bbolt.MustOpenBBolt(dstFile, jsonSerializer{})
+	t.Cleanup(func() { dst.Close() })
+
+	if err := dst.Restore(&buf); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	it, err := dst.GlobalEvents(0)
+	if err != nil {
+		t.Fatalf("GlobalEvents() error = %v", err)
+	}
+	var restored []core.Event
+	for it.Next() {
+		ev, err := it.Value()
+		if err != nil {
+			t.Fatalf("Value() error = %v", err)
+		}
+		restored = append(restored, ev)
+	}
+	it.Close()
+	if len(restored) != len(events) {
+		t.Fatalf("got %d restored events, want %d", len(restored), len(events))
+	}
+
+	// a Save continuing the restored aggregate must not collide with, or overwrite, the restored
+	// events - this is only possible if Restore resynced the bucket's sequence counter.
+	if err := dst.Save([]core.Event{newEvent("a", "1", 3)}); err != nil {
+		t.Fatalf("Save() after Restore error = %v", err)
+	}
+
+	it, err = dst.GlobalEvents(0)
+	if err != nil {
+		t.Fatalf("GlobalEvents() error = %v", err)
+	}
+	defer it.Close()
+	var count int
+	for it.Next() {
+		count++
+	}
+	if count != len(events)+1 {
+		t.Fatalf("got %d global events after resuming Save, want %d", count, len(events)+1)
+	}
+}
+
+func TestVacuumProducesReadableCopy(t *testing.T) {
+	es := openTestBBolt(t)
+	if err := es.Save([]core.Event{newEvent("a", "1", 1)}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "vacuumed.db")
+	if err := es.Vacuum(dest); err != nil {
+		t.Fatalf("Vacuum() error = %v", err)
+	}
+	if _, err := os.Stat(dest); err != nil {
+		t.Fatalf("vacuumed file missing: %v", err)
+	}
+
+	reopened := bbolt.MustOpenBBolt(dest, jsonSerializer{})
+	defer reopened.Close()
+
+	it, err := reopened.GlobalEvents(0)
+	if err != nil {
+		t.Fatalf("GlobalEvents() error = %v", err)
+	}
+	defer it.Close()
+	if !it.Next() {
+		t.Fatal("vacuumed copy has no events, want the one saved before Vacuum")
+	}
+}
@@ -0,0 +1,50 @@
+package bbolt_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hallgren/eventsourcing/core"
+	"github.com/hallgren/eventsourcing/eventstore/bbolt"
+)
+
+// otherSerializer is a distinct concrete base.Serializer type from jsonSerializer, even though it
+// encodes identically, so formatID tells the two apart.
+type otherSerializer struct{ jsonSerializer }
+
+func TestMigrateRewritesEventsToTheActiveSerializer(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "bolt.db")
+
+	old := bbolt.MustOpenBBolt(dbFile, otherSerializer{})
+	if err := old.Save([]core.Event{newEvent("a", "1", 1)}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	old.Close()
+
+	es := bbolt.MustOpenBBolt(dbFile, jsonSerializer{})
+	defer es.Close()
+
+	if err := es.Migrate(otherSerializer{}); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	it, err := es.GlobalEvents(0)
+	if err != nil {
+		t.Fatalf("GlobalEvents() error = %v", err)
+	}
+	defer it.Close()
+	if !it.Next() {
+		t.Fatal("migrated event missing from global order")
+	}
+	ev, err := it.Value()
+	if err != nil {
+		t.Fatalf("Value() error = %v, want the migrated event to decode cleanly with the new serializer", err)
+	}
+	if ev.AggregateID != "1" {
+		t.Fatalf("got %+v, want the original event", ev)
+	}
+
+	if err := es.Migrate(otherSerializer{}); err != nil {
+		t.Fatalf("second Migrate() error = %v, want a no-op migration to still succeed", err)
+	}
+}
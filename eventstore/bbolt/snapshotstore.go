@@ -0,0 +1,84 @@
+package bbolt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hallgren/eventsourcing/core"
+	"go.etcd.io/bbolt"
+)
+
+// snapshotBucketName stores one record per aggregate, keyed the same way as an aggregate's event
+// bucket, holding the latest snapshot SnapshotStore.Save (or SaveTx) was given for it.
+const snapshotBucketName = "snapshots"
+
+// SnapshotStore is a core.SnapshotStore backed by the same bolt database as a BBolt event store.
+// Opening it on the BBolt instance that also stores the events lets SaveTx enlist a snapshot
+// write in a transaction that already holds event writes - see BBolt.BeginTx.
+type SnapshotStore struct {
+	e *BBolt
+}
+
+// NewSnapshotStore opens the snapshot bucket in e's database, creating it if this is the first
+// time a snapshot is saved there.
+func NewSnapshotStore(e *BBolt) (*SnapshotStore, error) {
+	err := e.db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(snapshotBucketName))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &SnapshotStore{e: e}, nil
+}
+
+// Get returns the latest snapshot saved for id/aggregateType, or core.ErrSnapshotNotFound if none
+// has been saved yet.
+func (s *SnapshotStore) Get(ctx context.Context, id string, aggregateType string) (core.Snapshot, error) {
+	var snapshot core.Snapshot
+	err := s.e.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(snapshotBucketName))
+		value := bucket.Get([]byte(aggregateKey(aggregateType, id)))
+		if value == nil {
+			return core.ErrSnapshotNotFound
+		}
+		return s.e.serializer.Unmarshal(value, &snapshot)
+	})
+	return snapshot, err
+}
+
+// Save persists snapshot in its own bolt transaction, overwriting whatever was previously saved
+// for the same aggregate.
+func (s *SnapshotStore) Save(snapshot core.Snapshot) error {
+	tx, err := s.e.db.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.save(tx, snapshot); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SaveTx persists snapshot using tx instead of opening a new transaction, so the write commits (or
+// rolls back) together with whatever else tx holds - typically the event writes that made the
+// snapshot redundant, enlisted earlier through the same Tx via BBolt.BeginTx/Tx.Save. tx must come
+// from the same *BBolt instance this store was opened on.
+func (s *SnapshotStore) SaveTx(tx *Tx, snapshot core.Snapshot) error {
+	return s.save(tx.tx, snapshot)
+}
+
+func (s *SnapshotStore) save(tx *bbolt.Tx, snapshot core.Snapshot) error {
+	bucket := tx.Bucket([]byte(snapshotBucketName))
+	if bucket == nil {
+		return errors.New("snapshot bucket not found")
+	}
+	value, err := s.e.serializer.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("could not serialize snapshot: %w", err)
+	}
+	return bucket.Put([]byte(aggregateKey(snapshot.Type, snapshot.ID)), value)
+}
@@ -0,0 +1,107 @@
+package bbolt_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing/core"
+	"github.com/hallgren/eventsourcing/eventstore/bbolt"
+)
+
+func TestSnapshotStoreGetReturnsErrSnapshotNotFound(t *testing.T) {
+	es := openTestBBolt(t)
+	store, err := bbolt.NewSnapshotStore(es)
+	if err != nil {
+		t.Fatalf("NewSnapshotStore() error = %v", err)
+	}
+
+	_, err = store.Get(context.Background(), "1", "a")
+	if !errors.Is(err, core.ErrSnapshotNotFound) {
+		t.Fatalf("Get() error = %v, want core.ErrSnapshotNotFound", err)
+	}
+}
+
+func TestSnapshotStoreSaveRoundTrip(t *testing.T) {
+	es := openTestBBolt(t)
+	store, err := bbolt.NewSnapshotStore(es)
+	if err != nil {
+		t.Fatalf("NewSnapshotStore() error = %v", err)
+	}
+
+	snapshot := core.Snapshot{ID: "1", Type: "a", Version: 3, GlobalVersion: 3, State: []byte(`{"n":1}`)}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got, err := store.Get(context.Background(), "1", "a")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Version != snapshot.Version || string(got.State) != string(snapshot.State) {
+		t.Fatalf("got %+v, want %+v", got, snapshot)
+	}
+}
+
+func TestSnapshotStoreSaveTxCommitsWithEnlistedEvents(t *testing.T) {
+	es := openTestBBolt(t)
+	store, err := bbolt.NewSnapshotStore(es)
+	if err != nil {
+		t.Fatalf("NewSnapshotStore() error = %v", err)
+	}
+
+	tx, err := es.BeginTx()
+	if err != nil {
+		t.Fatalf("BeginTx() error = %v", err)
+	}
+
+	if err := tx.Save([]core.Event{newEvent("a", "1", 1)}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	snapshot := core.Snapshot{ID: "1", Type: "a", Version: 1, GlobalVersion: 1, State: []byte(`{}`)}
+	if err := store.SaveTx(tx, snapshot); err != nil {
+		t.Fatalf("SaveTx() error = %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	if _, err := store.Get(context.Background(), "1", "a"); err != nil {
+		t.Fatalf("Get() after commit error = %v", err)
+	}
+	it, err := es.GlobalEvents(0)
+	if err != nil {
+		t.Fatalf("GlobalEvents() error = %v", err)
+	}
+	defer it.Close()
+	if !it.Next() {
+		t.Fatal("enlisted event not visible after commit")
+	}
+}
+
+func TestSnapshotStoreSaveTxRollsBackWithEnlistedEvents(t *testing.T) {
+	es := openTestBBolt(t)
+	store, err := bbolt.NewSnapshotStore(es)
+	if err != nil {
+		t.Fatalf("NewSnapshotStore() error = %v", err)
+	}
+
+	tx, err := es.BeginTx()
+	if err != nil {
+		t.Fatalf("BeginTx() error = %v", err)
+	}
+	if err := tx.Save([]core.Event{newEvent("a", "1", 1)}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	snapshot := core.Snapshot{ID: "1", Type: "a", Version: 1, GlobalVersion: 1, State: []byte(`{}`)}
+	if err := store.SaveTx(tx, snapshot); err != nil {
+		t.Fatalf("SaveTx() error = %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	if _, err := store.Get(context.Background(), "1", "a"); !errors.Is(err, core.ErrSnapshotNotFound) {
+		t.Fatalf("Get() after rollback error = %v, want core.ErrSnapshotNotFound", err)
+	}
+}
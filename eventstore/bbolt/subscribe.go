@@ -0,0 +1,150 @@
+package bbolt
+
+import (
+	"context"
+
+	"github.com/hallgren/eventsourcing/core"
+)
+
+// subscribeConfig holds the options collected from a Subscribe call's SubscribeOptions.
+type subscribeConfig struct {
+	bufferSize     int
+	dropOldest     bool
+	aggregateTypes map[string]struct{}
+}
+
+func (c subscribeConfig) accepts(ev core.Event) bool {
+	if len(c.aggregateTypes) == 0 {
+		return true
+	}
+	_, ok := c.aggregateTypes[ev.AggregateType]
+	return ok
+}
+
+// SubscribeOption configures a Subscribe call.
+type SubscribeOption func(*subscribeConfig)
+
+// WithBuffer sets the size of the channel Subscribe delivers events on. The default is 16.
+func WithBuffer(n int) SubscribeOption {
+	return func(c *subscribeConfig) { c.bufferSize = n }
+}
+
+// WithDropOldest makes Subscribe drop the oldest buffered event to make room for a new one when
+// the subscriber can't keep up, instead of holding back newer events until it catches up. The
+// default is to wait for the subscriber; Save itself is never blocked by a slow subscriber.
+func WithDropOldest() SubscribeOption {
+	return func(c *subscribeConfig) { c.dropOldest = true }
+}
+
+// WithAggregateType restricts the subscription to events belonging to one of the given aggregate
+// types. With no filter applied, events of every aggregate type are delivered.
+func WithAggregateType(aggregateTypes ...string) SubscribeOption {
+	return func(c *subscribeConfig) {
+		for _, t := range aggregateTypes {
+			c.aggregateTypes[t] = struct{}{}
+		}
+	}
+}
+
+// Subscribe first replays every event from the global sequence start onwards and then blocks,
+// delivering new events as Save commits them, so that a projection can tail the store without
+// polling GlobalEvents itself. The returned event channel is closed, and any error is sent on the
+// error channel, once ctx is done or the store can no longer be read.
+func (e *BBolt) Subscribe(ctx context.Context, start uint64, opts ...SubscribeOption) (<-chan core.Event, <-chan error) {
+	cfg := subscribeConfig{bufferSize: 16, aggregateTypes: map[string]struct{}{}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	events := make(chan core.Event, cfg.bufferSize)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(events)
+		defer close(errs)
+
+		next := start
+		for {
+			// Capture the notification channel before replaying so that a commit landing while we
+			// replay still wakes us up, instead of being missed.
+			notify := e.wait()
+
+			n, err := e.replay(ctx, next, cfg, events)
+			next += n
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-notify:
+			}
+		}
+	}()
+
+	return events, errs
+}
+
+// replay delivers every event from the global sequence start through the current end of the
+// store, applying cfg's filter and backpressure policy, and returns how many positions were
+// advanced so the caller can resume from there on the next call.
+func (e *BBolt) replay(ctx context.Context, start uint64, cfg subscribeConfig, out chan core.Event) (uint64, error) {
+	it, err := e.GlobalEvents(start)
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+
+	var n uint64
+	for it.Next() {
+		ev, err := it.Value()
+		if err != nil {
+			return n, err
+		}
+		// measure by global sequence, not by events read, so a gap left by Compact is skipped
+		n = uint64(ev.GlobalVersion) - start
+
+		if !cfg.accepts(ev) {
+			continue
+		}
+		if !deliver(ctx, out, ev, cfg.dropOldest) {
+			return n, ctx.Err()
+		}
+	}
+	return n, nil
+}
+
+// deliver sends ev on out, applying the subscription's backpressure policy when out is full. It
+// returns false if ctx was cancelled before ev could be delivered.
+func deliver(ctx context.Context, out chan core.Event, ev core.Event, dropOldest bool) bool {
+	select {
+	case out <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	default:
+	}
+
+	if !dropOldest {
+		select {
+		case out <- ev:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	// out is full: drop the oldest queued event to make room for ev instead of waiting.
+	select {
+	case <-out:
+	default:
+	}
+	select {
+	case out <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,76 @@
+package bbolt_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing/core"
+	"github.com/hallgren/eventsourcing/eventstore/bbolt"
+)
+
+func drainEvent(t *testing.T, events <-chan core.Event, errs <-chan error) core.Event {
+	t.Helper()
+	select {
+	case ev := <-events:
+		return ev
+	case err := <-errs:
+		t.Fatalf("unexpected error from Subscribe: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for an event from Subscribe")
+	}
+	return core.Event{}
+}
+
+func TestSubscribeReplaysHistoryThenTailsLive(t *testing.T) {
+	es := openTestBBolt(t)
+	if err := es.Save([]core.Event{newEvent("a", "1", 1)}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, errs := es.Subscribe(ctx, 0)
+
+	historical := drainEvent(t, events, errs)
+	if historical.AggregateID != "1" || historical.Version != 1 {
+		t.Fatalf("got %+v, want the pre-existing event", historical)
+	}
+
+	if err := es.Save([]core.Event{newEvent("a", "1", 2)}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	live := drainEvent(t, events, errs)
+	if live.AggregateID != "1" || live.Version != 2 {
+		t.Fatalf("got %+v, want the event saved after subscribing", live)
+	}
+}
+
+func TestSubscribeFiltersByAggregateType(t *testing.T) {
+	es := openTestBBolt(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, errs := es.Subscribe(ctx, 0, bbolt.WithAggregateType("wanted"))
+
+	if err := es.Save([]core.Event{newEvent("ignored", "1", 1)}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := es.Save([]core.Event{newEvent("wanted", "1", 1)}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got := drainEvent(t, events, errs)
+	if got.AggregateType != "wanted" {
+		t.Fatalf("got aggregate type %q, want only \"wanted\" events delivered", got.AggregateType)
+	}
+
+	select {
+	case ev := <-events:
+		t.Fatalf("got unexpected second event %+v, filter should have dropped the \"ignored\" one", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,106 @@
+package bbolt_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing/core"
+)
+
+func newEvent(aggregateType, aggregateID string, version uint64) core.Event {
+	return core.Event{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Version:       core.Version(version),
+		Reason:        "TestEvent",
+		Timestamp:     time.Now().UTC(),
+		Data:          []byte(`{}`),
+	}
+}
+
+func TestSaveTxCommitsAllAggregatesAtomically(t *testing.T) {
+	es := openTestBBolt(t)
+
+	events := []core.Event{
+		newEvent("a", "1", 1),
+		newEvent("a", "1", 2),
+		newEvent("b", "1", 1),
+	}
+	if err := es.SaveTx(events); err != nil {
+		t.Fatalf("SaveTx() error = %v", err)
+	}
+
+	it, err := es.GlobalEvents(0)
+	if err != nil {
+		t.Fatalf("GlobalEvents() error = %v", err)
+	}
+	defer it.Close()
+
+	var count int
+	for it.Next() {
+		if _, err := it.Value(); err != nil {
+			t.Fatalf("Value() error = %v", err)
+		}
+		count++
+	}
+	if count != len(events) {
+		t.Fatalf("got %d global events, want %d", count, len(events))
+	}
+}
+
+func TestSaveTxRejectsWholeBatchOnConcurrencyConflict(t *testing.T) {
+	es := openTestBBolt(t)
+
+	events := []core.Event{
+		newEvent("a", "1", 1),
+		// "b" starts at version 2 instead of 1: a concurrency conflict
+		newEvent("b", "1", 2),
+	}
+	err := es.SaveTx(events)
+	if !errors.Is(err, core.ErrConcurrency) {
+		t.Fatalf("SaveTx() error = %v, want core.ErrConcurrency", err)
+	}
+
+	it, err := es.GlobalEvents(0)
+	if err != nil {
+		t.Fatalf("GlobalEvents() error = %v", err)
+	}
+	defer it.Close()
+
+	if it.Next() {
+		t.Fatal("expected no events to have been committed after a failed SaveTx")
+	}
+}
+
+func TestBeginTxEnlistsMultipleSaves(t *testing.T) {
+	es := openTestBBolt(t)
+
+	tx, err := es.BeginTx()
+	if err != nil {
+		t.Fatalf("BeginTx() error = %v", err)
+	}
+	if err := tx.Save([]core.Event{newEvent("a", "1", 1)}); err != nil {
+		t.Fatalf("Tx.Save() error = %v", err)
+	}
+	if err := tx.Save([]core.Event{newEvent("b", "1", 1)}); err != nil {
+		t.Fatalf("Tx.Save() error = %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Tx.Commit() error = %v", err)
+	}
+
+	it, err := es.GlobalEvents(0)
+	if err != nil {
+		t.Fatalf("GlobalEvents() error = %v", err)
+	}
+	defer it.Close()
+
+	var count int
+	for it.Next() {
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("got %d global events, want 2", count)
+	}
+}
@@ -0,0 +1,215 @@
+// Package tcp streams events saved through a core.EventStore to remote subscribers over a plain
+// TCP socket, framed with a length-prefixed JSON envelope. It gives operators a language-agnostic
+// way to fan events out to non-Go consumers without adopting a full message broker.
+package tcp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hallgren/eventsourcing/core"
+)
+
+// readFromGlobalSequence is the request verb a client sends to receive historical events starting
+// at a given global sequence number, e.g. "read-from-global-sequence 42\n".
+const readFromGlobalSequence = "read-from-global-sequence"
+
+// message is the wire representation of a single event.
+type message struct {
+	AggregateType string    `json:"aggregateType"`
+	AggregateID   string    `json:"aggregateID"`
+	Version       uint64    `json:"version"`
+	GlobalVersion uint64    `json:"globalVersion"`
+	Reason        string    `json:"reason"`
+	Timestamp     time.Time `json:"timestamp"`
+	Metadata      []byte    `json:"metadata"`
+	Data          []byte    `json:"data"`
+}
+
+func newMessage(ev core.Event) message {
+	return message{
+		AggregateType: ev.AggregateType,
+		AggregateID:   ev.AggregateID,
+		Version:       uint64(ev.Version),
+		GlobalVersion: uint64(ev.GlobalVersion),
+		Reason:        ev.Reason,
+		Timestamp:     ev.Timestamp,
+		Metadata:      ev.Metadata,
+		Data:          ev.Data,
+	}
+}
+
+// EventStore is the subset of core.EventStore that Server needs, plus GlobalEvents for replaying
+// history to a "read-from-global-sequence" request. core.EventStore itself has no GlobalEvents
+// method, but every store this package is meant to front (e.g. *bbolt.BBolt) implements one with
+// this signature.
+type EventStore interface {
+	core.EventStore
+	GlobalEvents(start uint64) (core.Iterator, error)
+}
+
+// Server wraps an EventStore and publishes every event saved through it, in global order, to all
+// currently connected subscribers. A connecting client that instead sends a
+// "read-from-global-sequence N" request is replied to with the historical events from N onwards,
+// by delegating to the wrapped store's GlobalEvents, and is then disconnected.
+type Server struct {
+	EventStore
+	listener net.Listener
+
+	mu          sync.Mutex
+	subscribers map[net.Conn]struct{}
+}
+
+// Listen starts a Server on addr (see net.Listen for the address format), wrapping store, and
+// begins accepting subscriber connections in the background. Call Close to stop it.
+func Listen(addr string, store EventStore) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		EventStore:  store,
+		listener:    l,
+		subscribers: map[net.Conn]struct{}{},
+	}
+	go s.acceptLoop()
+	return s, nil
+}
+
+// Save saves events through the wrapped store and, once committed, publishes them in global order
+// to every connected subscriber.
+func (s *Server) Save(events []core.Event) error {
+	if err := s.EventStore.Save(events); err != nil {
+		return err
+	}
+	for _, ev := range events {
+		s.publish(ev)
+	}
+	return nil
+}
+
+// Addr returns the address the server is listening on, useful when Listen was given port 0.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops accepting new subscribers and closes every existing connection.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.subscribers {
+		conn.Close()
+		delete(s.subscribers, conn)
+	}
+	return err
+}
+
+func (s *Server) acceptLoop() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	var from uint64
+	if n, _ := fmt.Sscanf(line, readFromGlobalSequence+" %d", &from); n == 1 {
+		s.replyHistory(conn, from)
+		conn.Close()
+		return
+	}
+
+	s.mu.Lock()
+	s.subscribers[conn] = struct{}{}
+	s.mu.Unlock()
+}
+
+// replyHistory streams every event from the wrapped store's global event order, starting at from,
+// to conn.
+func (s *Server) replyHistory(conn net.Conn, from uint64) {
+	it, err := s.GlobalEvents(from)
+	if err != nil {
+		return
+	}
+	defer it.Close()
+
+	for it.Next() {
+		ev, err := it.Value()
+		if err != nil {
+			return
+		}
+		if err := writeFrame(conn, newMessage(ev)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) publish(ev core.Event) {
+	msg := newMessage(ev)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.subscribers {
+		if err := writeFrame(conn, msg); err != nil {
+			conn.Close()
+			delete(s.subscribers, conn)
+		}
+	}
+}
+
+// writeFrame writes msg to w as a 4-byte big endian length prefix followed by its JSON encoding.
+func writeFrame(w io.Writer, msg message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(len(body)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+// ReadFrame reads a single length-prefixed event frame from r, as written by writeFrame. It is
+// exported so that Go-side consumers of the stream don't have to reimplement the framing.
+func ReadFrame(r io.Reader) (core.Event, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return core.Event{}, err
+	}
+	body := make([]byte, binary.BigEndian.Uint32(header))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return core.Event{}, err
+	}
+	msg := message{}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return core.Event{}, err
+	}
+	return core.Event{
+		AggregateType: msg.AggregateType,
+		AggregateID:   msg.AggregateID,
+		Version:       core.Version(msg.Version),
+		GlobalVersion: core.Version(msg.GlobalVersion),
+		Reason:        msg.Reason,
+		Timestamp:     msg.Timestamp,
+		Metadata:      msg.Metadata,
+		Data:          msg.Data,
+	}, nil
+}
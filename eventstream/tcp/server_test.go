@@ -0,0 +1,141 @@
+package tcp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing/core"
+	"github.com/hallgren/eventsourcing/eventstream/tcp"
+)
+
+// memStore is a minimal in-memory tcp.EventStore used to exercise Server without a real bbolt db.
+type memStore struct {
+	events []core.Event
+}
+
+func (m *memStore) Save(events []core.Event) error {
+	m.events = append(m.events, events...)
+	return nil
+}
+
+func (m *memStore) Get(ctx context.Context, id string, aggregateType string, afterVersion core.Version) (core.Iterator, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *memStore) GlobalEvents(start uint64) (core.Iterator, error) {
+	return &sliceIterator{events: m.events, start: start, pos: -1}, nil
+}
+
+// sliceIterator adapts a slice of events, whose GlobalVersion is their 1-based index, to
+// core.Iterator.
+type sliceIterator struct {
+	events []core.Event
+	start  uint64
+	pos    int
+}
+
+func (it *sliceIterator) Next() bool {
+	for {
+		it.pos++
+		if it.pos >= len(it.events) {
+			return false
+		}
+		if uint64(it.events[it.pos].GlobalVersion) >= it.start {
+			return true
+		}
+	}
+}
+
+func (it *sliceIterator) Value() (core.Event, error) {
+	return it.events[it.pos], nil
+}
+
+func (it *sliceIterator) Close() {}
+
+func testEvent(aggregateID string, globalVersion uint64) core.Event {
+	return core.Event{
+		AggregateID:   aggregateID,
+		AggregateType: "test",
+		Version:       core.Version(globalVersion),
+		GlobalVersion: core.Version(globalVersion),
+		Reason:        "TestEvent",
+		Timestamp:     time.Unix(int64(globalVersion), 0).UTC(),
+		Data:          []byte(`{}`),
+	}
+}
+
+func TestReadFromGlobalSequenceReplaysHistory(t *testing.T) {
+	store := &memStore{events: []core.Event{testEvent("a", 1), testEvent("a", 2), testEvent("b", 3)}}
+	s, err := tcp.Listen("127.0.0.1:0", store)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprintf(conn, "read-from-global-sequence %d\n", 2); err != nil {
+		t.Fatalf("write request: %v", err)
+	}
+
+	var got []core.Event
+	for {
+		ev, err := tcp.ReadFrame(conn)
+		if err != nil {
+			break
+		}
+		got = append(got, ev)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2 (global versions 2 and 3)", len(got))
+	}
+	if got[0].GlobalVersion != 2 || got[1].GlobalVersion != 3 {
+		t.Fatalf("got global versions %d,%d, want 2,3", got[0].GlobalVersion, got[1].GlobalVersion)
+	}
+}
+
+func TestSubscribeReceivesLiveSave(t *testing.T) {
+	store := &memStore{}
+	s, err := tcp.Listen("127.0.0.1:0", store)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprintf(conn, "subscribe\n"); err != nil {
+		t.Fatalf("write request: %v", err)
+	}
+
+	// the server registers the connection as a subscriber in its own goroutine, only after reading
+	// the line above; give it a moment before saving so the event is not published too early.
+	time.Sleep(100 * time.Millisecond)
+
+	ev := testEvent("a", 1)
+	if err := s.Save([]core.Event{ev}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	got, err := tcp.ReadFrame(conn)
+	if err != nil {
+		t.Fatalf("ReadFrame() error = %v", err)
+	}
+	if got.AggregateID != ev.AggregateID || got.GlobalVersion != ev.GlobalVersion {
+		t.Fatalf("got %+v, want %+v", got, ev)
+	}
+}
@@ -5,11 +5,24 @@ import (
 	"encoding/json"
 	"errors"
 	"reflect"
+	"time"
 
 	"github.com/hallgren/eventsourcing/core"
+	"github.com/hallgren/eventsourcing/eventstore/bbolt"
 	"github.com/hallgren/eventsourcing/internal"
 )
 
+// snapshotMetaType suffixes the aggregate type used to persist the bookkeeping SnapshotStrategy
+// needs (the version and timestamp of the last snapshot taken) in the same snapshot store, so it
+// survives restarts without requiring a second store.
+const snapshotMetaType = "_snapshot_meta"
+
+// snapshotMeta is the bookkeeping state stored under snapshotMetaType.
+type snapshotMeta struct {
+	Version   Version
+	Timestamp time.Time
+}
+
 // ErrUnsavedEvents aggregate events must be saved before creating snapshot
 var ErrUnsavedEvents = errors.New("aggregate holds unsaved events")
 
@@ -24,21 +37,44 @@ type SnapshotRepository struct {
 	snapshotStore   core.SnapshotStore
 	Serializer      SerializeFunc
 	Deserializer    DeserializeFunc
+	strategy        SnapshotStrategy
+	factories       map[string]func() aggregate
+}
+
+// Option configures optional behavior on a SnapshotRepository created via NewSnapshotRepository.
+type Option func(*SnapshotRepository)
+
+// AutoSnapshot makes Save consult strategy after events are saved and automatically persist a
+// snapshot when it fires, so callers no longer have to hand-roll their own snapshot cadence.
+func AutoSnapshot(strategy SnapshotStrategy) Option {
+	return func(s *SnapshotRepository) {
+		s.strategy = strategy
+	}
 }
 
 // NewSnapshotRepository factory function
-func NewSnapshotRepository(snapshotStore core.SnapshotStore, eventRepo *EventRepository) *SnapshotRepository {
-	return &SnapshotRepository{
+func NewSnapshotRepository(snapshotStore core.SnapshotStore, eventRepo *EventRepository, opts ...Option) *SnapshotRepository {
+	s := &SnapshotRepository{
 		snapshotStore:   snapshotStore,
 		eventRepository: eventRepo,
 		Serializer:      json.Marshal,
 		Deserializer:    json.Unmarshal,
+		factories:       map[string]func() aggregate{},
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // Register register the aggregate in the event repository
 func (s *SnapshotRepository) Register(a aggregate) {
 	s.eventRepository.Register(a)
+
+	t := reflect.TypeOf(a).Elem()
+	s.factories[internal.AggregateType(a)] = func() aggregate {
+		return reflect.New(t).Interface().(aggregate)
+	}
 }
 
 // EventRepository returns the underlying event repository. If the user wants to operate on the event repository
@@ -103,22 +139,173 @@ func (s *SnapshotRepository) getSnapshot(ctx context.Context, id string, a aggre
 	return nil
 }
 
-// Save will save aggregate events and snapshot
+// Save will save aggregate events and, depending on the repository's SnapshotStrategy, its snapshot
 func (s *SnapshotRepository) Save(a aggregate) error {
+	root := a.Root()
+	pending := append([]core.Event(nil), root.Events()...)
+
 	// make sure events are stored
-	err := s.eventRepository.Save(a)
+	if err := s.eventRepository.Save(a); err != nil {
+		return err
+	}
+
+	// no strategy configured, keep the historic behavior of snapshotting on every save
+	if s.strategy == nil {
+		return s.SaveSnapshot(a)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	id := root.ID()
+	aggregateType := internal.AggregateType(a)
+	lastVersion, lastTimestamp := s.lastSnapshotMeta(context.Background(), id, aggregateType)
+
+	take := false
+	for _, ev := range pending {
+		if s.strategy.ShouldTakeSnapshot(lastVersion, lastTimestamp, ev) {
+			take = true
+		}
+	}
+	if !take {
+		return nil
+	}
+	if err := s.SaveSnapshot(a); err != nil {
+		return err
+	}
+	// SaveSnapshot snapshots the aggregate's current version, which by now includes every event in
+	// pending, not just the one that tripped the strategy - record bookkeeping against the last one
+	// so a multi-event batch can't make the strategy fire more often than configured.
+	final := pending[len(pending)-1]
+	return s.saveSnapshotMeta(id, aggregateType, Version(final.Version), final.Timestamp)
+}
+
+// lastSnapshotMeta returns the version and timestamp of the last snapshot taken for id, or the
+// zero values if none has been taken yet.
+func (s *SnapshotRepository) lastSnapshotMeta(ctx context.Context, id, aggregateType string) (Version, time.Time) {
+	snap, err := s.snapshotStore.Get(ctx, id, aggregateType+snapshotMetaType)
+	if err != nil {
+		return 0, time.Time{}
+	}
+	meta := snapshotMeta{}
+	if err := json.Unmarshal(snap.State, &meta); err != nil {
+		return 0, time.Time{}
+	}
+	return meta.Version, meta.Timestamp
+}
+
+// saveSnapshotMeta persists the version and timestamp of the snapshot just taken for id so that
+// SnapshotStrategy keeps working across restarts.
+func (s *SnapshotRepository) saveSnapshotMeta(id, aggregateType string, version Version, timestamp time.Time) error {
+	state, err := json.Marshal(snapshotMeta{Version: version, Timestamp: timestamp})
+	if err != nil {
+		return err
+	}
+	return s.snapshotStore.Save(core.Snapshot{
+		ID:      id,
+		Type:    aggregateType + snapshotMetaType,
+		Version: core.Version(version),
+		State:   state,
+	})
+}
+
+// SnapshotAll scans every event for aggregateType in global order and snapshots the aggregates
+// that satisfy the repository's SnapshotStrategy. It is meant to be run as a batch job, e.g. to
+// backfill snapshots after registering a stricter strategy on an aggregate type with existing
+// history.
+func (s *SnapshotRepository) SnapshotAll(ctx context.Context, aggregateType string) error {
+	if s.strategy == nil {
+		return errors.New("no snapshot strategy configured")
+	}
+	newAggregate, ok := s.factories[aggregateType]
+	if !ok {
+		return errors.New("aggregate type not registered: " + aggregateType)
+	}
+
+	it, err := s.eventRepository.GlobalEvents(0)
 	if err != nil {
 		return err
 	}
+	defer it.Close()
+
+	pending := map[string][]core.Event{}
+	for it.Next() {
+		ev, err := it.Value()
+		if err != nil {
+			return err
+		}
+		if ev.AggregateType != aggregateType {
+			continue
+		}
+		pending[ev.AggregateID] = append(pending[ev.AggregateID], ev)
+	}
 
-	return s.SaveSnapshot(a)
+	for id, events := range pending {
+		lastVersion, lastTimestamp := s.lastSnapshotMeta(ctx, id, aggregateType)
+		take := false
+		for _, ev := range events {
+			if s.strategy.ShouldTakeSnapshot(lastVersion, lastTimestamp, ev) {
+				take = true
+			}
+		}
+		if !take {
+			continue
+		}
+
+		a := newAggregate()
+		if err := s.GetWithContext(ctx, id, a); err != nil {
+			return err
+		}
+		if err := s.SaveSnapshot(a); err != nil {
+			return err
+		}
+		// same as in Save: record bookkeeping against the last event actually covered by the
+		// snapshot, not whichever one tripped the strategy first.
+		final := events[len(events)-1]
+		if err := s.saveSnapshotMeta(id, aggregateType, Version(final.Version), final.Timestamp); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // SaveSnapshot will only store the snapshot and will return an error if there are events that are not stored
 func (s *SnapshotRepository) SaveSnapshot(a aggregate) error {
+	snapshot, err := s.buildSnapshot(a)
+	if err != nil {
+		return err
+	}
+	return s.snapshotStore.Save(snapshot)
+}
+
+// SaveSnapshotTx behaves like SaveSnapshot, but writes through tx instead of opening its own bolt
+// transaction, so the snapshot write can be enlisted alongside event writes a caller already made
+// on the same tx via bbolt.BBolt.BeginTx/Tx.Save - rather than committing the two separately.
+// tx must come from the same *bbolt.BBolt instance backing the repository's snapshot store, which
+// in turn must be a *bbolt.SnapshotStore.
+//
+// This only enlists the snapshot write: EventRepository.Save still commits the event write on its
+// own, since it does not accept a caller-supplied transaction, so true single-commit atomicity
+// across both stores requires calling tx.Save directly (as BBolt.SaveTx does internally) instead
+// of going through EventRepository for the aggregate being snapshotted.
+func (s *SnapshotRepository) SaveSnapshotTx(tx *bbolt.Tx, a aggregate) error {
+	store, ok := s.snapshotStore.(*bbolt.SnapshotStore)
+	if !ok {
+		return errors.New("SaveSnapshotTx requires a *bbolt.SnapshotStore")
+	}
+	snapshot, err := s.buildSnapshot(a)
+	if err != nil {
+		return err
+	}
+	return store.SaveTx(tx, snapshot)
+}
+
+// buildSnapshot serializes a into the core.Snapshot SaveSnapshot/SaveSnapshotTx persists. It
+// returns ErrUnsavedEvents if a has events that have not been saved yet.
+func (s *SnapshotRepository) buildSnapshot(a aggregate) (core.Snapshot, error) {
 	root := a.Root()
 	if len(root.Events()) > 0 {
-		return ErrUnsavedEvents
+		return core.Snapshot{}, ErrUnsavedEvents
 	}
 
 	state := []byte{}
@@ -128,23 +315,20 @@ func (s *SnapshotRepository) SaveSnapshot(a aggregate) error {
 	if ok {
 		state, err = sa.SerializeSnapshot(s.Serializer)
 		if err != nil {
-			return err
+			return core.Snapshot{}, err
 		}
 	} else {
 		state, err = s.Serializer(a)
 		if err != nil {
-			return err
+			return core.Snapshot{}, err
 		}
 	}
 
-	snapshot := core.Snapshot{
+	return core.Snapshot{
 		ID:            root.ID(),
 		Type:          internal.AggregateType(a),
 		Version:       core.Version(root.Version()),
 		GlobalVersion: core.Version(root.GlobalVersion()),
 		State:         state,
-	}
-
-	err = s.snapshotStore.Save(snapshot)
-	return err
+	}, nil
 }
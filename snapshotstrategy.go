@@ -0,0 +1,63 @@
+package eventsourcing
+
+import (
+	"time"
+
+	"github.com/hallgren/eventsourcing/core"
+)
+
+// SnapshotStrategy decides, after an event has been appended to an aggregate's stream, whether the
+// aggregate is due for a new snapshot. lastVersion and lastTimestamp describe the most recent
+// snapshot taken for the aggregate (the zero value if none has been taken yet).
+type SnapshotStrategy interface {
+	ShouldTakeSnapshot(lastVersion Version, lastTimestamp time.Time, ev core.Event) bool
+}
+
+type everyNEvents struct {
+	n core.Version
+}
+
+// EveryNEvents returns a SnapshotStrategy that fires once n events have been appended to an
+// aggregate since its last snapshot.
+func EveryNEvents(n uint64) SnapshotStrategy {
+	return everyNEvents{n: core.Version(n)}
+}
+
+func (s everyNEvents) ShouldTakeSnapshot(lastVersion Version, _ time.Time, ev core.Event) bool {
+	return ev.Version-core.Version(lastVersion) >= s.n
+}
+
+type everyDuration struct {
+	d time.Duration
+}
+
+// EveryDuration returns a SnapshotStrategy that fires once d has elapsed, measured by event
+// timestamp, since the last snapshot. It always fires for the very first event.
+func EveryDuration(d time.Duration) SnapshotStrategy {
+	return everyDuration{d: d}
+}
+
+func (s everyDuration) ShouldTakeSnapshot(_ Version, lastTimestamp time.Time, ev core.Event) bool {
+	if lastTimestamp.IsZero() {
+		return true
+	}
+	return ev.Timestamp.Sub(lastTimestamp) >= s.d
+}
+
+type compositeStrategy struct {
+	strategies []SnapshotStrategy
+}
+
+// Composite returns a SnapshotStrategy that fires as soon as any of strategies would.
+func Composite(strategies ...SnapshotStrategy) SnapshotStrategy {
+	return compositeStrategy{strategies: strategies}
+}
+
+func (s compositeStrategy) ShouldTakeSnapshot(lastVersion Version, lastTimestamp time.Time, ev core.Event) bool {
+	for _, strategy := range s.strategies {
+		if strategy.ShouldTakeSnapshot(lastVersion, lastTimestamp, ev) {
+			return true
+		}
+	}
+	return false
+}
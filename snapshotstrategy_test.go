@@ -0,0 +1,61 @@
+package eventsourcing_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/core"
+)
+
+func TestEveryNEvents(t *testing.T) {
+	strategy := eventsourcing.EveryNEvents(3)
+
+	cases := []struct {
+		lastVersion  eventsourcing.Version
+		eventVersion core.Version
+		want         bool
+	}{
+		{lastVersion: 0, eventVersion: 2, want: false},
+		{lastVersion: 0, eventVersion: 3, want: true},
+		{lastVersion: 0, eventVersion: 4, want: true},
+		{lastVersion: 10, eventVersion: 12, want: false},
+		{lastVersion: 10, eventVersion: 13, want: true},
+	}
+	for _, c := range cases {
+		ev := core.Event{Version: c.eventVersion}
+		got := strategy.ShouldTakeSnapshot(c.lastVersion, time.Time{}, ev)
+		if got != c.want {
+			t.Errorf("ShouldTakeSnapshot(lastVersion=%d, ev.Version=%d) = %v, want %v", c.lastVersion, c.eventVersion, got, c.want)
+		}
+	}
+}
+
+func TestEveryDuration(t *testing.T) {
+	strategy := eventsourcing.EveryDuration(time.Hour)
+	now := time.Now()
+
+	if !strategy.ShouldTakeSnapshot(0, time.Time{}, core.Event{Timestamp: now}) {
+		t.Error("ShouldTakeSnapshot() = false, want true when no snapshot has been taken yet")
+	}
+	if strategy.ShouldTakeSnapshot(0, now, core.Event{Timestamp: now.Add(30 * time.Minute)}) {
+		t.Error("ShouldTakeSnapshot() = true, want false before the duration has elapsed")
+	}
+	if !strategy.ShouldTakeSnapshot(0, now, core.Event{Timestamp: now.Add(90 * time.Minute)}) {
+		t.Error("ShouldTakeSnapshot() = false, want true once the duration has elapsed")
+	}
+}
+
+func TestComposite(t *testing.T) {
+	strategy := eventsourcing.Composite(eventsourcing.EveryNEvents(100), eventsourcing.EveryDuration(time.Hour))
+	now := time.Now()
+
+	// neither strategy fires
+	if strategy.ShouldTakeSnapshot(0, now, core.Event{Version: 1, Timestamp: now.Add(time.Minute)}) {
+		t.Error("ShouldTakeSnapshot() = true, want false when no branch fires")
+	}
+	// the duration branch fires even though the event-count branch doesn't
+	if !strategy.ShouldTakeSnapshot(0, now, core.Event{Version: 1, Timestamp: now.Add(2 * time.Hour)}) {
+		t.Error("ShouldTakeSnapshot() = false, want true when any branch fires")
+	}
+}